@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	_ "modernc.org/sqlite"
+)
+
+// Role gates which commands a user can run. Roles are ordered by
+// privilege: viewer < uploader < admin.
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleUploader Role = "uploader"
+	RoleAdmin    Role = "admin"
+)
+
+func (r Role) atLeast(min Role) bool {
+	rank := map[Role]int{RoleViewer: 0, RoleUploader: 1, RoleAdmin: 2}
+	return rank[r] >= rank[min]
+}
+
+// userRecord is one row of users.db.
+type userRecord struct {
+	TelegramUserID int64
+	Role           Role
+	PasswordHash   string // argon2id, "salt:hash" base64-encoded (unpadded)
+	CreatedAt      time.Time
+	LastLoginAt    time.Time
+}
+
+// userStore is the SQLite-backed table of registered users, replacing the
+// single global password + isFirstTimeSetup flag this bot used to have.
+type userStore struct {
+	db *sql.DB
+}
+
+func newUserStore(path string) (*userStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open users db: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS users (
+		telegram_user_id INTEGER PRIMARY KEY,
+		role TEXT NOT NULL,
+		password_hash TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		last_login_at TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create users table: %v", err)
+	}
+	return &userStore{db: db}, nil
+}
+
+func (s *userStore) get(telegramUserID int64) (*userRecord, error) {
+	row := s.db.QueryRow(`SELECT telegram_user_id, role, password_hash, created_at, last_login_at FROM users WHERE telegram_user_id = ?`, telegramUserID)
+	u := &userRecord{}
+	var lastLogin sql.NullTime
+	if err := row.Scan(&u.TelegramUserID, &u.Role, &u.PasswordHash, &u.CreatedAt, &lastLogin); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if lastLogin.Valid {
+		u.LastLoginAt = lastLogin.Time
+	}
+	return u, nil
+}
+
+func (s *userStore) put(u *userRecord) error {
+	_, err := s.db.Exec(`INSERT INTO users (telegram_user_id, role, password_hash, created_at, last_login_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(telegram_user_id) DO UPDATE SET role = excluded.role, password_hash = excluded.password_hash`,
+		u.TelegramUserID, u.Role, u.PasswordHash, u.CreatedAt, u.LastLoginAt)
+	return err
+}
+
+func (s *userStore) delete(telegramUserID int64) error {
+	_, err := s.db.Exec(`DELETE FROM users WHERE telegram_user_id = ?`, telegramUserID)
+	return err
+}
+
+func (s *userStore) touchLogin(telegramUserID int64) error {
+	_, err := s.db.Exec(`UPDATE users SET last_login_at = ? WHERE telegram_user_id = ?`, time.Now(), telegramUserID)
+	return err
+}
+
+func (s *userStore) hasAdmin() (bool, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = ?`, RoleAdmin).Scan(&count)
+	return count > 0, err
+}
+
+// Argon2id parameters per the OWASP minimum recommendation.
+const (
+	argon2Time    = 1
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return base64.RawStdEncoding.EncodeToString(salt) + ":" + base64.RawStdEncoding.EncodeToString(hash), nil
+}
+
+func verifyPassword(password, encoded string) bool {
+	parts := splitOnce(encoded, ':')
+	if parts == nil {
+		return false
+	}
+	saltB64, hashB64 := parts[0], parts[1]
+
+	salt, err := base64.RawStdEncoding.DecodeString(saltB64)
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(hashB64)
+	if err != nil {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+func splitOnce(s string, sep byte) []string {
+	for i := 0; i < len(s); i++ {
+		if s[i] == sep {
+			return []string{s[:i], s[i+1:]}
+		}
+	}
+	return nil
+}
+
+func randomToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
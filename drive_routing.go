@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+)
+
+// folderRouteStore persists the chatID -> folderID mapping that routes each
+// Telegram user's uploads into their own "MinecraftMods/<username>/"
+// subfolder instead of a shared root.
+type folderRouteStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newFolderRouteStore(path string) *folderRouteStore {
+	return &folderRouteStore{path: path}
+}
+
+func (s *folderRouteStore) load() (map[int64]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routes := make(map[int64]string)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return routes, nil
+		}
+		return nil, err
+	}
+	if len(data) == 0 {
+		return routes, nil
+	}
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+func (s *folderRouteStore) save(routes map[int64]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(routes, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func (s *folderRouteStore) get(chatID int64) (string, bool, error) {
+	routes, err := s.load()
+	if err != nil {
+		return "", false, err
+	}
+	folderID, ok := routes[chatID]
+	return folderID, ok, nil
+}
+
+func (s *folderRouteStore) put(chatID int64, folderID string) error {
+	routes, err := s.load()
+	if err != nil {
+		return err
+	}
+	routes[chatID] = folderID
+	return s.save(routes)
+}
+
+var unsafeFolderNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+func sanitizeFolderName(username string) string {
+	if username == "" {
+		return "unknown"
+	}
+	return unsafeFolderNameChars.ReplaceAllString(username, "_")
+}
+
+// folderForChat returns the Drive folder a chat's uploads should land in,
+// auto-creating "<root>/<username>/" under the manager's root folder the
+// first time a given chat is seen.
+func (dm *DriveManager) folderForChat(routes *folderRouteStore, chatID int64, username string) (string, error) {
+	if folderID, ok, err := routes.get(chatID); err != nil {
+		return "", err
+	} else if ok {
+		return folderID, nil
+	}
+
+	subfolder := sanitizeFolderName(username)
+	folderID, err := createOrGetFolder(dm.uploadService, subfolder, dm.folderID, dm.teamDriveID)
+	if err != nil {
+		return "", fmt.Errorf("unable to create per-user folder: %v", err)
+	}
+	if err := routes.put(chatID, folderID); err != nil {
+		return "", err
+	}
+	return folderID, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// dropboxStorage is the Storage backend for Dropbox, using its API v2
+// directly (no official Go SDK dependency) with a long-lived access token.
+type dropboxStorage struct {
+	client     *http.Client
+	token      string
+	folderPath string // e.g. "/MinecraftMods"
+}
+
+func newDropboxStorage() (*dropboxStorage, error) {
+	token := os.Getenv("DROPBOX_ACCESS_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("DROPBOX_ACCESS_TOKEN must be set")
+	}
+	return &dropboxStorage{
+		client:     http.DefaultClient,
+		token:      token,
+		folderPath: envOrDefault("DROPBOX_FOLDER", "/MinecraftMods"),
+	}, nil
+}
+
+func (s *dropboxStorage) Type() string { return "dropbox" }
+
+func (s *dropboxStorage) do(method, url string, body io.Reader, apiArg any, extraHeaders map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	if apiArg != nil {
+		argJSON, err := json.Marshal(apiArg)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Dropbox-API-Arg", string(argJSON))
+	}
+	for k, v := range extraHeaders {
+		req.Header.Set(k, v)
+	}
+	return s.client.Do(req)
+}
+
+func (s *dropboxStorage) Put(name string, r io.Reader, size int64) (FileRef, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return FileRef{}, err
+	}
+
+	arg := map[string]any{
+		"path": s.folderPath + "/" + name,
+		"mode": "overwrite",
+	}
+	resp, err := s.do(http.MethodPost, "https://content.dropboxapi.com/2/files/upload", bytes.NewReader(data), arg,
+		map[string]string{"Content-Type": "application/octet-stream"})
+	if err != nil {
+		return FileRef{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return FileRef{}, fmt.Errorf("dropbox upload failed with status %d", resp.StatusCode)
+	}
+
+	var meta struct {
+		ID             string `json:"id"`
+		Name           string `json:"name"`
+		Size           int64  `json:"size"`
+		ServerModified string `json:"server_modified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return FileRef{}, err
+	}
+	modTime, _ := time.Parse(time.RFC3339, meta.ServerModified)
+	return FileRef{ID: meta.ID, Name: meta.Name, Size: meta.Size, ModTime: modTime}, nil
+}
+
+func (s *dropboxStorage) List(ctx context.Context) ([]FileRef, error) {
+	arg := map[string]any{"path": s.folderPath}
+	body, err := json.Marshal(arg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.dropboxapi.com/2/files/list_folder", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("dropbox list failed with status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Entries []struct {
+			ID             string `json:"id"`
+			Name           string `json:"name"`
+			Size           int64  `json:"size"`
+			ServerModified string `json:"server_modified"`
+		} `json:"entries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	refs := make([]FileRef, 0, len(payload.Entries))
+	for _, e := range payload.Entries {
+		modTime, _ := time.Parse(time.RFC3339, e.ServerModified)
+		refs = append(refs, FileRef{ID: e.ID, Name: e.Name, Size: e.Size, ModTime: modTime})
+	}
+	return refs, nil
+}
+
+func (s *dropboxStorage) Delete(id string) error {
+	arg := map[string]any{"path": id}
+	body, err := json.Marshal(arg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://api.dropboxapi.com/2/files/delete_v2", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("dropbox delete failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *dropboxStorage) Get(id string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodPost, "https://content.dropboxapi.com/2/files/download", nil, map[string]any{"path": id}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("dropbox download failed with status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
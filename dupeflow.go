@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// pendingDuplicateUpload holds an upload paused because it collides with an
+// already-indexed (modID, version, loader) while the user is asked whether
+// to overwrite, keep both, or skip.
+type pendingDuplicateUpload struct {
+	ChatID     int64
+	SenderName string
+	FileName   string
+	Data       []byte
+	Meta       *ModMetadata
+	Existing   *modIndexEntry
+	CreatedAt  time.Time
+}
+
+type pendingUploadStore struct {
+	mu      sync.Mutex
+	pending map[string]*pendingDuplicateUpload
+}
+
+func newPendingUploadStore() *pendingUploadStore {
+	return &pendingUploadStore{pending: make(map[string]*pendingDuplicateUpload)}
+}
+
+func (s *pendingUploadStore) put(token string, p *pendingDuplicateUpload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[token] = p
+}
+
+func (s *pendingUploadStore) take(token string) (*pendingDuplicateUpload, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.pending[token]
+	if ok {
+		delete(s.pending, token)
+	}
+	return p, ok
+}
+
+func duplicatePromptMarkup(token string) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		tele.Btn{Text: "✅ Overwrite", Data: "dupe_overwrite:" + token},
+		tele.Btn{Text: "📑 Keep both", Data: "dupe_keep:" + token},
+		tele.Btn{Text: "⏭ Skip", Data: "dupe_skip:" + token},
+	))
+	return markup
+}
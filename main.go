@@ -1,13 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/subtle"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,7 +24,19 @@ import (
 type DriveManager struct {
 	readService   *drive.Service // Service Account для чтения
 	uploadService *drive.Service // OAuth2 для загрузки
+	httpClient    *http.Client   // OAuth2 client, reused for raw resumable upload requests
 	folderID      string
+	teamDriveID   string // non-empty when uploads/listing target a Shared Drive
+}
+
+// driveListQuery applies the Shared Drive scoping every Files.List call in
+// this package needs, whether or not a Team Drive is configured.
+func (dm *DriveManager) driveListQuery(call *drive.FilesListCall) *drive.FilesListCall {
+	call = call.SupportsAllDrives(true).IncludeItemsFromAllDrives(true)
+	if dm.teamDriveID != "" {
+		return call.Corpora("drive").DriveId(dm.teamDriveID)
+	}
+	return call.Corpora("allDrives")
 }
 
 type UploadSession struct {
@@ -33,22 +47,77 @@ type UploadSession struct {
 }
 
 var uploadSessions = make(map[int64]*UploadSession)
-var isFirstTimeSetup = true
+var uploadState = newUploadStateStore(envOrDefault("UPLOAD_STATE_PATH", "uploads_state.json"))
+var folderRoutes = newFolderRouteStore(envOrDefault("FOLDER_ROUTES_PATH", "folder_routes.json"))
+var activeSyncWorker *syncWorker
+
+const sessionIdleTimeout = 30 * time.Minute
+const maxLoginAttempts = 5
 
-func init() {
-	// Проверяем, был ли уже введен пароль ранее
-	if _, err := os.Stat("password_entered.flag"); err == nil {
-		isFirstTimeSetup = false
+var sessions = newSessionManager(sessionIdleTimeout, maxLoginAttempts)
+var users *userStore
+var modIdx *modIndex
+var pendingUploads = newPendingUploadStore()
+
+// bootstrapToken is non-empty only until the first admin account is
+// created, after which /bootstrap refuses to run again.
+var bootstrapToken string
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return fallback
 }
 
 func main() {
 	godotenv.Load()
 
-	// Initialize Google Drive service
-	driveManager, err := initGoogleDrive()
+	var err error
+	users, err = newUserStore(envOrDefault("USERS_DB_PATH", "users.db"))
+	if err != nil {
+		log.Fatal("Failed to initialize user store:", err)
+	}
+
+	hasAdmin, err := users.hasAdmin()
+	if err != nil {
+		log.Fatal("Failed to inspect user store:", err)
+	}
+	if !hasAdmin {
+		bootstrapToken = os.Getenv("ADMIN_BOOTSTRAP_TOKEN")
+		if bootstrapToken == "" {
+			bootstrapToken = randomToken()
+		}
+		fmt.Printf("🔑 No admin account yet. Bootstrap token (use /bootstrap <token> <password> in a DM with the bot):\n%s\n", bootstrapToken)
+	}
+
+	modIdx, err = newModIndex(envOrDefault("MOD_INDEX_DB_PATH", "mod_index.db"))
+	if err != nil {
+		log.Fatal("Failed to initialize mod index:", err)
+	}
+
+	// Initialize the configured storage backend (Drive by default)
+	storageBackend, err := NewStorage(context.Background(), currentBackendName(), true)
 	if err != nil {
-		log.Fatal("Failed to initialize Google Drive:", err)
+		log.Fatal("Failed to initialize storage backend:", err)
+	}
+
+	// Two-way local mirror sync is a Drive-only, opt-in feature: only starts
+	// when LOCAL_MIRROR_DIR is configured.
+	if mirrorDir := os.Getenv("LOCAL_MIRROR_DIR"); mirrorDir != "" {
+		if dm, ok := storageBackend.(*DriveManager); ok {
+			interval := 5 * time.Minute
+			if secs := os.Getenv("SYNC_INTERVAL_SECONDS"); secs != "" {
+				if n, perr := time.ParseDuration(secs + "s"); perr == nil {
+					interval = n
+				}
+			}
+			activeSyncWorker = newSyncWorker(dm, mirrorDir, interval)
+			activeSyncWorker.Start()
+			fmt.Printf("Two-way sync started: %s <-> Drive folder %s (every %v). Note: every run re-lists and re-hashes both sides in full — this does NOT yet use Drive's Changes API to sync incrementally.\n", mirrorDir, dm.folderID, interval)
+		} else {
+			fmt.Println("LOCAL_MIRROR_DIR is set but the active backend isn't Drive; sync disabled")
+		}
 	}
 
 	pref := tele.Settings{
@@ -81,7 +150,7 @@ func main() {
 
 	// Basic start command
 	b.Handle("/start", func(c tele.Context) error {
-		return c.Send("Welcome to the Mod Uploader Bot! 🎮\n\nCommands:\n/upload - Start uploading .jar files to Google Drive (requires password)\n/done - Finish uploading session\n/list - List all uploaded mods\n/quantity - Get the number of uploaded mods\n\n🔒 Authentication required for uploading files.")
+		return c.Send("Welcome to the Mod Uploader Bot! 🎮\n\nCommands:\n/upload - Start uploading .jar files to Google Drive (requires password)\n/done - Finish uploading session\n/list [mc:<version>] [loader:<name>] - List uploaded mods, optionally filtered\n/search <query> - Search uploaded mods by name/id/filename\n/mod <modid> - Show every upload of a given mod\n/quantity - Get the number of uploaded mods\n/delete <name> - Delete a mod (uploader/admin)\n/resume - List uploads interrupted by a dropped connection or restart\n/drive - List or switch the Shared Drive (admin only)\n/backend - List or switch the storage backend (switching is admin only)\n/sync - Control the two-way local mirror (now/status/pause/resume)\n/bootstrap <token> <password> - One-time admin account setup\n/adduser, /revoke - Manage accounts (admin only)\n\n🔒 Authentication required for uploading files.")
 	})
 
 	// Upload command - starts upload session for .jar files
@@ -96,11 +165,22 @@ func main() {
 			startTime:   time.Now(),
 		}
 
-		if isFirstTimeSetup {
-			return c.Send("🔑 Please enter the upload password to continue:")
-		} else {
+		session := sessions.get(chatID)
+		if session.state == stateAuthenticated && session.role.atLeast(RoleUploader) {
+			sessions.touch(chatID)
 			return c.Send("✅ Upload session started!\n\nPlease send your .jar files now. I'll upload each one to Google Drive.\n\nUse /done when you're finished uploading, or /cancel to cancel the session.")
 		}
+
+		user, err := users.get(c.Sender().ID)
+		if err != nil {
+			return c.Send("Failed to look up account: " + err.Error())
+		}
+		if user == nil {
+			return c.Send("🔒 No account found for you. Ask an admin to run /adduser for your Telegram user ID: " + fmt.Sprint(c.Sender().ID))
+		}
+
+		sessions.beginLogin(chatID, c.Sender().ID)
+		return c.Send("🔑 Please enter your password to continue:")
 	})
 
 	// Done command - ends upload session
@@ -136,6 +216,63 @@ func main() {
 		return c.Send(fmt.Sprintf("❌ Upload session cancelled.\n\n📊 Files uploaded before cancellation: %d", session.uploadCount))
 	})
 
+	// performUpload uploads already-downloaded jar bytes via the current backend
+	// (routing per-chat folders and the chunked resumable path for Drive, same
+	// as a direct OnDocument upload), then indexes mod metadata when present.
+	performUpload := func(chatID int64, username, fileName string, data []byte, meta *ModMetadata) error {
+		progressMsg, err := b.Send(tele.ChatID(chatID), fmt.Sprintf("⏳ Uploading %s...", fileName))
+		if err != nil {
+			return err
+		}
+
+		fileRef := FileRef{ID: fileName, Name: fileName}
+		reader := bytes.NewReader(data)
+		if dm, ok := storageBackend.(*DriveManager); ok {
+			folderID, ferr := dm.folderForChat(folderRoutes, chatID, username)
+			if ferr != nil {
+				return ferr
+			}
+			if err := dm.uploadFileResumableTo(b, uploadState, chatID, folderID, fileName, reader, progressMsg); err != nil {
+				return err
+			}
+			if files, lerr := dm.listFilesIn(folderID); lerr == nil {
+				for _, f := range files {
+					if f.Name == fileName {
+						fileRef = FileRef{ID: f.Id, Name: f.Name}
+						break
+					}
+				}
+			}
+			if meta != nil && fileRef.ID != fileName {
+				dm.applyModMetadata(fileRef.ID, meta)
+			}
+		} else if resumable, ok := storageBackend.(ResumableStorage); ok {
+			if err := resumable.PutResumable(b, uploadState, chatID, fileName, reader, progressMsg); err != nil {
+				return err
+			}
+		} else {
+			ref, err := storageBackend.Put(fileName, reader, int64(len(data)))
+			if err != nil {
+				return err
+			}
+			fileRef = ref
+		}
+
+		if meta != nil && modIdx != nil {
+			modIdx.put(&modIndexEntry{
+				FileID: fileRef.ID, FileName: fileName, ModID: meta.ModID, Name: meta.Name,
+				Version: meta.Version, MCVersion: meta.MCVersion, Loader: meta.Loader,
+				Authors: strings.Join(meta.Authors, ", "), Dependencies: strings.Join(meta.Dependencies, ", "),
+				UploadedAt: time.Now(),
+			})
+		}
+
+		if session, ok := uploadSessions[chatID]; ok {
+			session.uploadCount++
+		}
+		return nil
+	}
+
 	// Handle document uploads
 	b.Handle(tele.OnDocument, func(c tele.Context) error {
 		chatID := c.Chat().ID
@@ -146,10 +283,12 @@ func main() {
 			return c.Send("No active upload session found. Use /upload to start uploading files.")
 		}
 
-		// Check if authenticated
-		if isFirstTimeSetup {
-			return c.Send("🔒 Please authenticate first with the password. Use /upload and enter the password.")
+		// Check if authenticated with at least uploader privileges
+		authSession := sessions.get(chatID)
+		if authSession.state != stateAuthenticated || !authSession.role.atLeast(RoleUploader) {
+			return c.Send("🔒 Please authenticate first with your password. Use /upload and enter it.")
 		}
+		sessions.touch(chatID)
 
 		doc := c.Message().Document
 
@@ -158,64 +297,172 @@ func main() {
 			return c.Send("Please send only .jar files.")
 		}
 
-		// Send upload progress message
-		progressMsg := fmt.Sprintf("⏳ Uploading %s... (%d files uploaded so far)", doc.FileName, session.uploadCount)
-		c.Send(progressMsg)
-
-		// Download the file
+		// Download the whole file up front: mod metadata extraction needs to read
+		// it as a zip before it's handed to the (possibly streaming) upload path.
 		reader, err := b.File(&doc.File)
 		if err != nil {
 			return c.Send("Failed to get file reader: " + err.Error())
 		}
-
-		// Upload to Google Drive
-		err = driveManager.uploadFile(doc.FileName, reader)
+		data, err := io.ReadAll(reader)
 		if err != nil {
-			return c.Send("Failed to upload to Google Drive: " + err.Error())
+			return c.Send("Failed to download file: " + err.Error())
 		}
 
-		// Update session count
-		session.uploadCount++
+		meta, _ := extractModMetadata(data) // best-effort; not every .jar is a recognizable mod
+
+		if meta != nil && meta.ModID != "" && modIdx != nil {
+			if dup, derr := modIdx.findDuplicate(meta.ModID, meta.Version, meta.Loader); derr == nil && dup != nil {
+				token := randomToken()
+				pendingUploads.put(token, &pendingDuplicateUpload{
+					ChatID: chatID, SenderName: c.Sender().Username, FileName: doc.FileName,
+					Data: data, Meta: meta, Existing: dup, CreatedAt: time.Now(),
+				})
+				return c.Send(fmt.Sprintf("⚠️ %s %s (%s) for %s is already uploaded as %s.\n\nWhat would you like to do?",
+					meta.ModID, meta.Version, meta.Loader, meta.MCVersion, dup.FileName), duplicatePromptMarkup(token))
+			}
+		}
 
-		return c.Send(fmt.Sprintf("✅ Successfully uploaded %s to Google Drive!\n\n📊 Total files uploaded: %d\n\nSend more .jar files or use /done to finish.", doc.FileName, session.uploadCount))
+		if err := performUpload(chatID, c.Sender().Username, doc.FileName, data, meta); err != nil {
+			return c.Send(fmt.Sprintf("Failed to upload to %s: %s", storageBackend.Type(), err.Error()))
+		}
+
+		return c.Send(fmt.Sprintf("✅ Successfully uploaded %s to %s!\n\n📊 Total files uploaded: %d\n\nSend more .jar files or use /done to finish.", doc.FileName, storageBackend.Type(), session.uploadCount))
 	})
 
-	// Handle text messages (for password authentication)
+	// Duplicate-upload decision buttons (overwrite / keep both / skip)
+	b.Handle(tele.OnCallback, func(c tele.Context) error {
+		data := c.Callback().Data
+		var action, token string
+		switch {
+		case strings.HasPrefix(data, "dupe_overwrite:"):
+			action, token = "overwrite", strings.TrimPrefix(data, "dupe_overwrite:")
+		case strings.HasPrefix(data, "dupe_keep:"):
+			action, token = "keep", strings.TrimPrefix(data, "dupe_keep:")
+		case strings.HasPrefix(data, "dupe_skip:"):
+			action, token = "skip", strings.TrimPrefix(data, "dupe_skip:")
+		default:
+			return nil
+		}
+
+		pending, ok := pendingUploads.take(token)
+		if !ok {
+			return c.Respond(&tele.CallbackResponse{Text: "This decision already expired."})
+		}
+
+		switch action {
+		case "skip":
+			c.Edit("⏭ Skipped " + pending.FileName)
+			return c.Respond()
+		case "overwrite":
+			if err := storageBackend.Delete(pending.Existing.FileID); err != nil {
+				c.Edit("Failed to remove the existing file: " + err.Error())
+				return c.Respond()
+			}
+			if err := modIdx.deleteByFileID(pending.Existing.FileID); err != nil {
+				c.Edit("Failed to update the mod index: " + err.Error())
+				return c.Respond()
+			}
+		case "keep":
+			pending.FileName = strings.TrimSuffix(pending.FileName, ".jar") + "-" + randomToken()[:6] + ".jar"
+		}
+
+		if err := performUpload(pending.ChatID, pending.SenderName, pending.FileName, pending.Data, pending.Meta); err != nil {
+			c.Edit("Failed to upload: " + err.Error())
+			return c.Respond()
+		}
+		c.Edit("✅ Uploaded " + pending.FileName)
+		return c.Respond()
+	})
+
+	// Handle text messages (for password authentication). Input is only ever
+	// treated as a password while the per-chat session is in awaitingPassword
+	// state — everything else is ignored, so this never double-guesses plain
+	// chat messages as login attempts.
 	b.Handle(tele.OnText, func(c tele.Context) error {
 		chatID := c.Chat().ID
-		session, exists := uploadSessions[chatID]
+		authSession := sessions.get(chatID)
+		if authSession.state != stateAwaitingPassword {
+			return nil
+		}
 
-		// Check if there's an active upload session waiting for password
-		if !exists || !session.isActive || !isFirstTimeSetup {
-			return nil // Ignore text messages if no session or password already entered
+		if remaining := sessions.backoffRemaining(chatID); remaining > 0 {
+			return c.Send(fmt.Sprintf("🔒 Too many attempts. Try again in %s.", remaining.Round(time.Second)))
 		}
 
-		uploadPassword := os.Getenv("upload_password")
-		if uploadPassword == "" {
-			uploadPassword = "password" // Default password if not set
+		user, err := users.get(authSession.telegramUserID)
+		if err != nil {
+			return c.Send("Failed to look up account: " + err.Error())
+		}
+		if user == nil || !verifyPassword(c.Text(), user.PasswordHash) {
+			sessions.recordFailure(chatID)
+			return c.Send("❌ Incorrect password. Use /upload to try again.")
 		}
 
-		// Check password
-		if c.Text() == uploadPassword {
-			// Создаем флаг, что пароль введен
-			file, err := os.Create("password_entered.flag")
-			if err == nil {
-				file.Close()
-			}
-			isFirstTimeSetup = false
+		sessions.authenticate(chatID, user.Role)
+		users.touchLogin(user.TelegramUserID)
 
-			return c.Send("✅ UBERIIIIIIIIIIIIIIII\n\n📤 Upload session started!\n\nPlease send your .jar files now. I'll upload each one to Google Drive.\n\nUse /done when you're finished uploading, or /cancel to cancel the session.")
-		} else {
-			// Wrong password - end session
-			session.isActive = false
-			delete(uploadSessions, chatID)
-			return c.Send("❌ Incorrect password. Upload session cancelled.\n\nUse /upload to try again.")
-		}
+		return c.Send("✅ Authenticated!\n\n📤 Upload session started!\n\nPlease send your .jar files now. I'll upload each one to Google Drive.\n\nUse /done when you're finished uploading, or /cancel to cancel the session.")
 	})
 
-	// List command - shows all uploaded mods
+	// requireRole reports whether chatID is authenticated with at least min
+	// privilege, sending a rejection message itself when it isn't.
+	requireRole := func(c tele.Context, min Role) bool {
+		authSession := sessions.get(c.Chat().ID)
+		if authSession.state != stateAuthenticated || !authSession.role.atLeast(min) {
+			c.Send("🔒 This command requires a " + string(min) + " account. Use /upload to authenticate.")
+			return false
+		}
+		sessions.touch(c.Chat().ID)
+		return true
+	}
+
+	// filesForChat lists the current backend's files, scoped to the calling chat's
+	// own Drive subfolder when the backend supports per-user folder routing.
+	filesForChat := func(c tele.Context) ([]FileRef, error) {
+		if dm, ok := storageBackend.(*DriveManager); ok {
+			folderID, err := dm.folderForChat(folderRoutes, c.Chat().ID, c.Sender().Username)
+			if err != nil {
+				return nil, err
+			}
+			driveFiles, err := dm.listFilesIn(folderID)
+			if err != nil {
+				return nil, err
+			}
+			refs := make([]FileRef, 0, len(driveFiles))
+			for _, f := range driveFiles {
+				refs = append(refs, FileRef{ID: f.Id, Name: f.Name})
+			}
+			return refs, nil
+		}
+		return storageBackend.List(context.Background())
+	}
+
+	// List command - shows all uploaded mods. With "mc:<version>" and/or
+	// "loader:<name>" tokens in the payload, filters against the local mod
+	// index instead of listing the raw backend folder.
 	b.Handle("/list", func(c tele.Context) error {
-		files, err := driveManager.listFiles()
+		if !requireRole(c, RoleViewer) {
+			return nil
+		}
+
+		mcVersion, loader := parseListFilters(c.Message().Payload)
+		if mcVersion != "" || loader != "" {
+			entries, err := modIdx.filtered(mcVersion, loader)
+			if err != nil {
+				return c.Send("Failed to query mod index: " + err.Error())
+			}
+			if len(entries) == 0 {
+				return c.Send("No mods match that filter.")
+			}
+			var out strings.Builder
+			out.WriteString("📁 Matching Mods:\n\n")
+			for i, e := range entries {
+				out.WriteString(fmt.Sprintf("%d. %s (%s, %s %s)\n", i+1, e.FileName, e.Version, e.Loader, e.MCVersion))
+			}
+			return c.Send(out.String())
+		}
+
+		files, err := filesForChat(c)
 		if err != nil {
 			return c.Send("Failed to get file list: " + err.Error())
 		}
@@ -233,9 +480,82 @@ func main() {
 		return c.Send(fileList.String())
 	})
 
+	// Search command - full-text lookup across the local mod index
+	b.Handle("/search", func(c tele.Context) error {
+		if !requireRole(c, RoleViewer) {
+			return nil
+		}
+		query := strings.TrimSpace(c.Message().Payload)
+		if query == "" {
+			return c.Send("Usage: /search <query>")
+		}
+		entries, err := modIdx.search(query)
+		if err != nil {
+			return c.Send("Search failed: " + err.Error())
+		}
+		if len(entries) == 0 {
+			return c.Send("No mods matched \"" + query + "\".")
+		}
+		var out strings.Builder
+		out.WriteString(fmt.Sprintf("🔎 Results for \"%s\":\n\n", query))
+		for i, e := range entries {
+			out.WriteString(fmt.Sprintf("%d. %s — %s (%s %s)\n", i+1, e.FileName, e.Version, e.Loader, e.MCVersion))
+		}
+		return c.Send(out.String())
+	})
+
+	// Mod command - all known uploads of a single mod ID
+	b.Handle("/mod", func(c tele.Context) error {
+		if !requireRole(c, RoleViewer) {
+			return nil
+		}
+		modID := strings.TrimSpace(c.Message().Payload)
+		if modID == "" {
+			return c.Send("Usage: /mod <modid>")
+		}
+		entries, err := modIdx.byModID(modID)
+		if err != nil {
+			return c.Send("Lookup failed: " + err.Error())
+		}
+		if len(entries) == 0 {
+			return c.Send("No uploads found for mod \"" + modID + "\".")
+		}
+		var out strings.Builder
+		out.WriteString(fmt.Sprintf("📦 %s:\n\n", modID))
+		for _, e := range entries {
+			out.WriteString(fmt.Sprintf("• %s — %s, %s %s (by %s)\n", e.Version, e.FileName, e.Loader, e.MCVersion, e.Authors))
+		}
+		return c.Send(out.String())
+	})
+
+	// Resume command - lists uploads interrupted by a dropped connection or bot restart
+	b.Handle("/resume", func(c tele.Context) error {
+		pending, err := uploadState.forChat(c.Chat().ID)
+		if err != nil {
+			return c.Send("Failed to read interrupted uploads: " + err.Error())
+		}
+		if len(pending) == 0 {
+			return c.Send("No interrupted uploads for this chat.")
+		}
+
+		var list strings.Builder
+		list.WriteString("⏸️ Interrupted uploads:\n\n")
+		for _, st := range pending {
+			percent := float64(0)
+			if st.TotalSize > 0 {
+				percent = float64(st.Offset) / float64(st.TotalSize) * 100
+			}
+			list.WriteString(fmt.Sprintf("• %s — %.1f%% (resend the file to continue)\n", st.FileName, percent))
+		}
+		return c.Send(list.String())
+	})
+
 	// Quantity command - shows number of uploaded mods
 	b.Handle("/quantity", func(c tele.Context) error {
-		files, err := driveManager.listFiles()
+		if !requireRole(c, RoleViewer) {
+			return nil
+		}
+		files, err := filesForChat(c)
 		if err != nil {
 			return c.Send("Failed to get file count: " + err.Error())
 		}
@@ -243,10 +563,201 @@ func main() {
 		return c.Send(fmt.Sprintf("📊 Total number of uploaded mods: %d", len(files)))
 	})
 
+	// Sync command - controls the two-way local mirror worker (LOCAL_MIRROR_DIR)
+	b.Handle("/sync", func(c tele.Context) error {
+		if activeSyncWorker == nil {
+			return c.Send("Two-way sync isn't configured (set LOCAL_MIRROR_DIR).")
+		}
+
+		action := strings.TrimSpace(c.Message().Payload)
+		if action == "" {
+			action = "status"
+		}
+		if action != "status" && !requireRole(c, RoleAdmin) {
+			return nil
+		}
+
+		switch action {
+		case "now":
+			go activeSyncWorker.runOnce()
+			return c.Send("🔄 Sync started.")
+		case "pause":
+			activeSyncWorker.setPaused(true)
+			return c.Send("⏸️ Sync paused.")
+		case "resume":
+			activeSyncWorker.setPaused(false)
+			return c.Send("▶️ Sync resumed.")
+		case "status":
+			return c.Send(activeSyncWorker.status())
+		default:
+			return c.Send("Usage: /sync [now|status|pause|resume]")
+		}
+	})
+
+	// Drive command - lists Shared Drives the account can see, or switches the
+	// active one by ID or name. Only meaningful for the Drive backend.
+	b.Handle("/drive", func(c tele.Context) error {
+		dm, ok := storageBackend.(*DriveManager)
+		if !ok {
+			return c.Send("The current backend (" + storageBackend.Type() + ") doesn't use Shared Drives.")
+		}
+		if !requireRole(c, RoleAdmin) {
+			return nil
+		}
+
+		drives, err := dm.uploadService.Drives.List().PageSize(100).Do()
+		if err != nil {
+			return c.Send("Failed to list Shared Drives: " + err.Error())
+		}
+
+		requested := strings.TrimSpace(c.Message().Payload)
+		if requested == "" {
+			if len(drives.Drives) == 0 {
+				return c.Send("No Shared Drives visible to this account.")
+			}
+			var list strings.Builder
+			list.WriteString("📂 Shared Drives:\n\n")
+			for _, d := range drives.Drives {
+				list.WriteString(fmt.Sprintf("• %s (ID: %s)\n", d.Name, d.Id))
+			}
+			list.WriteString("\nUse /drive <id|name> to switch.")
+			return c.Send(list.String())
+		}
+
+		var target string
+		for _, d := range drives.Drives {
+			if d.Id == requested || strings.EqualFold(d.Name, requested) {
+				target = d.Id
+				break
+			}
+		}
+		if target == "" {
+			return c.Send("No Shared Drive matching " + requested)
+		}
+
+		dm.teamDriveID = target
+		folderID, err := createOrGetFolder(dm.uploadService, envOrDefault("folder_name", "MinecraftMods"), "", target)
+		if err != nil {
+			return c.Send("Switched drive but failed to resolve root folder: " + err.Error())
+		}
+		dm.folderID = folderID
+		return c.Send("✅ Now targeting Shared Drive " + requested)
+	})
+
+	// Backend command - lists or switches the active storage backend
+	b.Handle("/backend", func(c tele.Context) error {
+		requested := strings.TrimSpace(c.Message().Payload)
+		if requested == "" {
+			return c.Send(fmt.Sprintf("Current backend: %s\n\nAvailable: %s\n\nUse /backend <name> to switch.", storageBackend.Type(), strings.Join(availableBackends(), ", ")))
+		}
+		if !requireRole(c, RoleAdmin) {
+			return nil
+		}
+
+		next, err := NewStorage(context.Background(), requested, false)
+		if err != nil {
+			return c.Send("Failed to switch backend: " + err.Error())
+		}
+		storageBackend = next
+		return c.Send(fmt.Sprintf("✅ Switched storage backend to %s", storageBackend.Type()))
+	})
+
+	// Delete command - removes an uploaded mod by name or backend ID
+	b.Handle("/delete", func(c tele.Context) error {
+		if !requireRole(c, RoleUploader) {
+			return nil
+		}
+		id := strings.TrimSpace(c.Message().Payload)
+		if id == "" {
+			return c.Send("Usage: /delete <name or ID>")
+		}
+		target := id
+		if files, err := filesForChat(c); err == nil {
+			for _, f := range files {
+				if f.Name == id {
+					target = f.ID
+					break
+				}
+			}
+		}
+		if err := storageBackend.Delete(target); err != nil {
+			return c.Send("Failed to delete: " + err.Error())
+		}
+		return c.Send("🗑️ Deleted " + id)
+	})
+
+	// Bootstrap command - one-time admin account creation, guarded by ADMIN_BOOTSTRAP_TOKEN
+	b.Handle("/bootstrap", func(c tele.Context) error {
+		if bootstrapToken == "" {
+			return c.Send("An admin account already exists; /bootstrap is disabled.")
+		}
+		parts := strings.Fields(c.Message().Payload)
+		if len(parts) != 2 {
+			return c.Send("Usage: /bootstrap <token> <password>")
+		}
+		token, password := parts[0], parts[1]
+		if subtle.ConstantTimeCompare([]byte(token), []byte(bootstrapToken)) != 1 {
+			return c.Send("❌ Invalid bootstrap token.")
+		}
+
+		hash, err := hashPassword(password)
+		if err != nil {
+			return c.Send("Failed to hash password: " + err.Error())
+		}
+		if err := users.put(&userRecord{TelegramUserID: c.Sender().ID, Role: RoleAdmin, PasswordHash: hash, CreatedAt: time.Now()}); err != nil {
+			return c.Send("Failed to create admin account: " + err.Error())
+		}
+		bootstrapToken = ""
+		return c.Send("✅ Admin account created. Use /upload to authenticate.")
+	})
+
+	// Adduser command (admin only) - registers a new user with a role and password
+	b.Handle("/adduser", func(c tele.Context) error {
+		if !requireRole(c, RoleAdmin) {
+			return nil
+		}
+		parts := strings.Fields(c.Message().Payload)
+		if len(parts) != 3 {
+			return c.Send("Usage: /adduser <telegram_user_id> <admin|uploader|viewer> <password>")
+		}
+		telegramUserID, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return c.Send("Invalid Telegram user ID: " + parts[0])
+		}
+		role := Role(parts[1])
+		if role != RoleAdmin && role != RoleUploader && role != RoleViewer {
+			return c.Send("Role must be admin, uploader, or viewer.")
+		}
+		hash, err := hashPassword(parts[2])
+		if err != nil {
+			return c.Send("Failed to hash password: " + err.Error())
+		}
+		if err := users.put(&userRecord{TelegramUserID: telegramUserID, Role: role, PasswordHash: hash, CreatedAt: time.Now()}); err != nil {
+			return c.Send("Failed to add user: " + err.Error())
+		}
+		return c.Send(fmt.Sprintf("✅ Added user %d as %s", telegramUserID, role))
+	})
+
+	// Revoke command (admin only) - deletes a user and logs out any active sessions
+	b.Handle("/revoke", func(c tele.Context) error {
+		if !requireRole(c, RoleAdmin) {
+			return nil
+		}
+		telegramUserID, err := strconv.ParseInt(strings.TrimSpace(c.Message().Payload), 10, 64)
+		if err != nil {
+			return c.Send("Usage: /revoke <telegram_user_id>")
+		}
+		if err := users.delete(telegramUserID); err != nil {
+			return c.Send("Failed to revoke user: " + err.Error())
+		}
+		sessions.invalidateUser(telegramUserID)
+		return c.Send(fmt.Sprintf("✅ Revoked user %d", telegramUserID))
+	})
+
 	b.Start()
 }
 
-func initGoogleDrive() (*DriveManager, error) {
+func initGoogleDrive(allowInteractive bool) (*DriveManager, error) {
 	ctx := context.Background()
 
 	// Инициализация OAuth2 для загрузки (ОБЯЗАТЕЛЬНО)
@@ -265,7 +776,10 @@ func initGoogleDrive() (*DriveManager, error) {
 		return nil, fmt.Errorf("unable to parse oauth credentials: %v", err)
 	}
 
-	client := getClient(config)
+	client, err := getClient("google", config, allowInteractive)
+	if err != nil {
+		return nil, err
+	}
 	uploadService, err := drive.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return nil, fmt.Errorf("unable to create upload service: %v", err)
@@ -306,6 +820,11 @@ func initGoogleDrive() (*DriveManager, error) {
 		readService = uploadService // Используем OAuth2 для всего
 	}
 
+	teamDriveID := os.Getenv("TEAM_DRIVE_ID")
+	if teamDriveID != "" {
+		fmt.Printf("Targeting Shared Drive: %s\n", teamDriveID)
+	}
+
 	// Определяем folder ID
 	folderID := os.Getenv("folder_id")
 	if folderID != "" {
@@ -315,7 +834,7 @@ func initGoogleDrive() (*DriveManager, error) {
 		if folderName == "" {
 			folderName = "MinecraftMods"
 		}
-		folderID, err = createOrGetFolder(uploadService, folderName)
+		folderID, err = createOrGetFolder(uploadService, folderName, "", teamDriveID)
 		if err != nil {
 			return nil, fmt.Errorf("unable to create/get folder: %v", err)
 		}
@@ -324,20 +843,31 @@ func initGoogleDrive() (*DriveManager, error) {
 	return &DriveManager{
 		readService:   readService,
 		uploadService: uploadService,
+		httpClient:    client,
 		folderID:      folderID,
+		teamDriveID:   teamDriveID,
 	}, nil
 }
 
-func createOrGetFolder(srv *drive.Service, folderName string) (string, error) {
-	// Check if folder already exists (search in all locations, not just root)
+// createOrGetFolder finds (or creates) a folder named folderName. When
+// parentID is set the search/creation is scoped under that parent, which is
+// how per-user subfolders get routed under the root MinecraftMods folder.
+// When teamDriveID is set, the search and creation both target that Shared
+// Drive instead of My Drive.
+func createOrGetFolder(srv *drive.Service, folderName, parentID, teamDriveID string) (string, error) {
 	query := fmt.Sprintf("name='%s' and mimeType='application/vnd.google-apps.folder' and trashed=false", folderName)
-	r, err := srv.Files.List().
-		Q(query).
-		PageSize(1000).
-		SupportsAllDrives(true).
-		IncludeItemsFromAllDrives(true).
-		Corpora("allDrives").
-		Do()
+	if parentID != "" {
+		query += fmt.Sprintf(" and '%s' in parents", parentID)
+	}
+
+	listCall := srv.Files.List().Q(query).PageSize(1000).SupportsAllDrives(true).IncludeItemsFromAllDrives(true)
+	if teamDriveID != "" {
+		listCall = listCall.Corpora("drive").DriveId(teamDriveID)
+	} else {
+		listCall = listCall.Corpora("allDrives")
+	}
+
+	r, err := listCall.Do()
 	if err != nil {
 		return "", err
 	}
@@ -354,6 +884,11 @@ func createOrGetFolder(srv *drive.Service, folderName string) (string, error) {
 		Name:     folderName,
 		MimeType: "application/vnd.google-apps.folder",
 	}
+	if parentID != "" {
+		folder.Parents = []string{parentID}
+	} else if teamDriveID != "" {
+		folder.Parents = []string{teamDriveID}
+	}
 
 	file, err := srv.Files.Create(folder).
 		SupportsAllDrives(true).
@@ -367,9 +902,15 @@ func createOrGetFolder(srv *drive.Service, folderName string) (string, error) {
 }
 
 func (dm *DriveManager) uploadFile(fileName string, reader io.Reader) error {
+	return dm.uploadFileTo(dm.folderID, fileName, reader)
+}
+
+// uploadFileTo uploads into a specific folder rather than the manager's
+// default root, which is how per-chat folder routing places a file.
+func (dm *DriveManager) uploadFileTo(folderID, fileName string, reader io.Reader) error {
 	file := &drive.File{
 		Name:    fileName,
-		Parents: []string{dm.folderID},
+		Parents: []string{folderID},
 	}
 
 	_, err := dm.uploadService.Files.Create(file).
@@ -380,11 +921,14 @@ func (dm *DriveManager) uploadFile(fileName string, reader io.Reader) error {
 }
 
 func (dm *DriveManager) listFiles() ([]*drive.File, error) {
-	r, err := dm.readService.Files.List().
-		Q(fmt.Sprintf("'%s' in parents and trashed=false", dm.folderID)).
-		SupportsAllDrives(true).
-		IncludeItemsFromAllDrives(true).
-		Corpora("allDrives").
+	return dm.listFilesIn(dm.folderID)
+}
+
+// listFilesIn lists a specific folder rather than the manager's default
+// root, which is how per-chat folder routing scopes /list and /quantity.
+func (dm *DriveManager) listFilesIn(folderID string) ([]*drive.File, error) {
+	r, err := dm.driveListQuery(dm.readService.Files.List()).
+		Q(fmt.Sprintf("'%s' in parents and trashed=false", folderID)).
 		Fields("files(id,name,mimeType,owners(emailAddress))").
 		Do()
 	if err != nil {
@@ -393,14 +937,83 @@ func (dm *DriveManager) listFiles() ([]*drive.File, error) {
 	return r.Files, nil
 }
 
-func getClient(config *oauth2.Config) *http.Client {
-	tokFile := "token.json"
-	tok, err := tokenFromFile(tokFile)
+// applyModMetadata stamps extracted mod metadata onto the Drive file itself
+// (Description + AppProperties), so it's visible and filterable from the
+// Drive UI even without the local mod index.
+func (dm *DriveManager) applyModMetadata(fileID string, meta *ModMetadata) error {
+	update := &drive.File{
+		Description: fmt.Sprintf("%s %s (%s, MC %s)", meta.Name, meta.Version, meta.Loader, meta.MCVersion),
+		AppProperties: map[string]string{
+			"modid":     meta.ModID,
+			"mcversion": meta.MCVersion,
+			"loader":    meta.Loader,
+			"version":   meta.Version,
+		},
+	}
+	_, err := dm.uploadService.Files.Update(fileID, update).SupportsAllDrives(true).Do()
+	return err
+}
+
+// The Storage interface methods below let DriveManager stand in as the
+// "drive" backend behind the STORAGE_BACKEND abstraction, alongside
+// localStorage and the other cloud backends.
+
+func (dm *DriveManager) Type() string { return "drive" }
+
+func (dm *DriveManager) Put(name string, r io.Reader, size int64) (FileRef, error) {
+	if err := dm.uploadFile(name, r); err != nil {
+		return FileRef{}, err
+	}
+	return FileRef{ID: name, Name: name, Size: size, ModTime: time.Now()}, nil
+}
+
+func (dm *DriveManager) List(ctx context.Context) ([]FileRef, error) {
+	files, err := dm.listFiles()
 	if err != nil {
+		return nil, err
+	}
+	refs := make([]FileRef, 0, len(files))
+	for _, f := range files {
+		refs = append(refs, FileRef{ID: f.Id, Name: f.Name})
+	}
+	return refs, nil
+}
+
+func (dm *DriveManager) Delete(id string) error {
+	return dm.uploadService.Files.Delete(id).SupportsAllDrives(true).Do()
+}
+
+func (dm *DriveManager) Get(id string) (io.ReadCloser, error) {
+	resp, err := dm.readService.Files.Get(id).SupportsAllDrives(true).Download()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (dm *DriveManager) PutResumable(bot *tele.Bot, store *uploadStateStore, chatID int64, name string, r io.Reader, progressMsg *tele.Message) error {
+	return dm.uploadFileResumable(bot, store, chatID, name, r, progressMsg)
+}
+
+// getClient returns an HTTP client authorized for provider. When
+// allowInteractive is false, a missing cached token is a hard error instead
+// of falling through to the stdin-blocking authorization prompt — telebot's
+// poller dispatches updates on a single goroutine, so blocking on
+// fmt.Scan from a live command handler freezes the whole bot until restart.
+// Only the one-time startup path may set allowInteractive.
+func getClient(provider string, config *oauth2.Config, allowInteractive bool) (*http.Client, error) {
+	cache := newTokenCache(provider)
+	tok := &oauth2.Token{}
+	if err := cache.load(tok); err != nil {
+		if !allowInteractive {
+			return nil, fmt.Errorf("no cached OAuth token for %q; authorize it out-of-band first (e.g. start the bot once with STORAGE_BACKEND=%s set so it can run the interactive flow) before switching to it live", provider, provider)
+		}
 		tok = getTokenFromWeb(config)
-		saveToken(tokFile, tok)
+		if err := cache.save(tok); err != nil {
+			log.Fatalf("Unable to cache oauth token: %v", err)
+		}
 	}
-	return config.Client(context.Background(), tok)
+	return config.Client(context.Background(), tok), nil
 }
 
 func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
@@ -419,24 +1032,3 @@ func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
 	}
 	return tok
 }
-
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
-}
-
-func saveToken(path string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", path)
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalf("Unable to cache oauth token: %v", err)
-	}
-	defer f.Close()
-	json.NewEncoder(f).Encode(token)
-}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// modIndexEntry is one row of the local mod index, keyed by the backend's
+// file ID so /search, /mod, and filtered /list never have to re-scan Drive.
+type modIndexEntry struct {
+	FileID       string
+	FileName     string
+	ModID        string
+	Name         string
+	Version      string
+	MCVersion    string
+	Loader       string
+	Authors      string // comma-joined; this index is read-heavy and local-only
+	Dependencies string
+	UploadedAt   time.Time
+}
+
+type modIndex struct {
+	db *sql.DB
+}
+
+func newModIndex(path string) (*modIndex, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open mod index: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS mod_index (
+		file_id TEXT PRIMARY KEY,
+		file_name TEXT NOT NULL,
+		mod_id TEXT NOT NULL,
+		name TEXT,
+		version TEXT,
+		mc_version TEXT,
+		loader TEXT,
+		authors TEXT,
+		dependencies TEXT,
+		uploaded_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create mod_index table: %v", err)
+	}
+	return &modIndex{db: db}, nil
+}
+
+func (idx *modIndex) put(e *modIndexEntry) error {
+	_, err := idx.db.Exec(`INSERT INTO mod_index (file_id, file_name, mod_id, name, version, mc_version, loader, authors, dependencies, uploaded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(file_id) DO UPDATE SET file_name=excluded.file_name, mod_id=excluded.mod_id, name=excluded.name,
+			version=excluded.version, mc_version=excluded.mc_version, loader=excluded.loader,
+			authors=excluded.authors, dependencies=excluded.dependencies`,
+		e.FileID, e.FileName, e.ModID, e.Name, e.Version, e.MCVersion, e.Loader, e.Authors, e.Dependencies, e.UploadedAt)
+	return err
+}
+
+// deleteByFileID removes an entry, used when a duplicate upload is resolved
+// by overwriting the existing backend file so its stale row doesn't keep
+// surfacing in /search, /mod, and filtered /list.
+func (idx *modIndex) deleteByFileID(fileID string) error {
+	_, err := idx.db.Exec(`DELETE FROM mod_index WHERE file_id = ?`, fileID)
+	return err
+}
+
+// findDuplicate reports an existing entry with the same (modID, version,
+// loader) triple, so the upload handler can ask whether to overwrite, keep
+// both, or skip instead of silently shadowing an existing release.
+func (idx *modIndex) findDuplicate(modID, version, loader string) (*modIndexEntry, error) {
+	row := idx.db.QueryRow(`SELECT file_id, file_name, mod_id, name, version, mc_version, loader, authors, dependencies, uploaded_at
+		FROM mod_index WHERE mod_id = ? AND version = ? AND loader = ?`, modID, version, loader)
+	e := &modIndexEntry{}
+	if err := row.Scan(&e.FileID, &e.FileName, &e.ModID, &e.Name, &e.Version, &e.MCVersion, &e.Loader, &e.Authors, &e.Dependencies, &e.UploadedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func (idx *modIndex) byModID(modID string) ([]*modIndexEntry, error) {
+	return idx.query(`SELECT file_id, file_name, mod_id, name, version, mc_version, loader, authors, dependencies, uploaded_at
+		FROM mod_index WHERE mod_id = ? ORDER BY uploaded_at DESC`, modID)
+}
+
+func (idx *modIndex) search(query string) ([]*modIndexEntry, error) {
+	like := "%" + query + "%"
+	return idx.query(`SELECT file_id, file_name, mod_id, name, version, mc_version, loader, authors, dependencies, uploaded_at
+		FROM mod_index WHERE mod_id LIKE ? OR name LIKE ? OR file_name LIKE ? ORDER BY uploaded_at DESC`, like, like, like)
+}
+
+// filtered supports the /list mc:1.20.1 loader:fabric syntax: either filter
+// may be empty to mean "any".
+func (idx *modIndex) filtered(mcVersion, loader string) ([]*modIndexEntry, error) {
+	query := `SELECT file_id, file_name, mod_id, name, version, mc_version, loader, authors, dependencies, uploaded_at FROM mod_index WHERE 1=1`
+	var args []any
+	if mcVersion != "" {
+		query += " AND mc_version = ?"
+		args = append(args, mcVersion)
+	}
+	if loader != "" {
+		query += " AND loader = ?"
+		args = append(args, loader)
+	}
+	query += " ORDER BY uploaded_at DESC"
+	return idx.query(query, args...)
+}
+
+func (idx *modIndex) query(query string, args ...any) ([]*modIndexEntry, error) {
+	rows, err := idx.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*modIndexEntry
+	for rows.Next() {
+		e := &modIndexEntry{}
+		if err := rows.Scan(&e.FileID, &e.FileName, &e.ModID, &e.Name, &e.Version, &e.MCVersion, &e.Loader, &e.Authors, &e.Dependencies, &e.UploadedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// parseListFilters extracts "mc:1.20.1" / "loader:fabric" tokens from a
+// /list payload, e.g. "/list mc:1.20.1 loader:fabric".
+func parseListFilters(payload string) (mcVersion, loader string) {
+	for _, tok := range strings.Fields(payload) {
+		switch {
+		case strings.HasPrefix(tok, "mc:"):
+			mcVersion = strings.TrimPrefix(tok, "mc:")
+		case strings.HasPrefix(tok, "loader:"):
+			loader = strings.TrimPrefix(tok, "loader:")
+		}
+	}
+	return mcVersion, loader
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ModMetadata is what we can learn about a .jar before it ever reaches
+// Drive, pulled from whichever loader manifest the jar ships.
+type ModMetadata struct {
+	ModID        string
+	Name         string
+	Version      string
+	MCVersion    string
+	Loader       string // fabric, quilt, forge, or neoforge (legacy mcmod.info jars report forge)
+	Dependencies []string
+	Authors      []string
+}
+
+// extractModMetadata reads a .jar's loader manifest (fabric.mod.json,
+// quilt.mod.json, META-INF/mods.toml, or the legacy mcmod.info) and returns
+// whatever mod metadata it can find. Returns nil, nil when the jar carries
+// none of the known manifests — not every .jar is a recognizable mod.
+func extractModMetadata(jarBytes []byte) (*ModMetadata, error) {
+	zr, err := zip.NewReader(bytes.NewReader(jarBytes), int64(len(jarBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid jar/zip: %v", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	if f, ok := files["fabric.mod.json"]; ok {
+		return parseFabricModJSON(f)
+	}
+	if f, ok := files["quilt.mod.json"]; ok {
+		return parseQuiltModJSON(f)
+	}
+	if f, ok := files["META-INF/mods.toml"]; ok {
+		return parseModsToml(f)
+	}
+	if f, ok := files["mcmod.info"]; ok {
+		return parseMcmodInfo(f)
+	}
+	return nil, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func parseFabricModJSON(f *zip.File) (*ModMetadata, error) {
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		ID      string         `json:"id"`
+		Name    string         `json:"name"`
+		Version string         `json:"version"`
+		Depends map[string]any `json:"depends"`
+		Authors []any          `json:"authors"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing fabric.mod.json: %v", err)
+	}
+
+	meta := &ModMetadata{
+		ModID:   raw.ID,
+		Name:    raw.Name,
+		Version: raw.Version,
+		Loader:  "fabric",
+		Authors: stringifyAuthors(raw.Authors),
+	}
+	for dep, constraint := range raw.Depends {
+		if dep == "minecraft" {
+			meta.MCVersion = fmt.Sprint(constraint)
+			continue
+		}
+		meta.Dependencies = append(meta.Dependencies, dep)
+	}
+	return meta, nil
+}
+
+func parseQuiltModJSON(f *zip.File) (*ModMetadata, error) {
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw struct {
+		QuiltLoader struct {
+			ID       string `json:"id"`
+			Version  string `json:"version"`
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Depends []struct {
+				ID       string `json:"id"`
+				Versions string `json:"versions"`
+			} `json:"depends"`
+		} `json:"quilt_loader"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing quilt.mod.json: %v", err)
+	}
+
+	meta := &ModMetadata{
+		ModID:   raw.QuiltLoader.ID,
+		Name:    raw.QuiltLoader.Metadata.Name,
+		Version: raw.QuiltLoader.Version,
+		Loader:  "quilt",
+	}
+	for _, dep := range raw.QuiltLoader.Depends {
+		if dep.ID == "minecraft" {
+			meta.MCVersion = dep.Versions
+			continue
+		}
+		meta.Dependencies = append(meta.Dependencies, dep.ID)
+	}
+	return meta, nil
+}
+
+// Forge/NeoForge's mods.toml is full TOML, but the fields we need are all
+// simple `key = "value"` pairs inside the [[mods]] table, so a small regex
+// scan is enough without pulling in a TOML parser dependency. The first
+// match of each key is kept, since [[dependencies]] entries also carry a
+// modId. The MC version is the versionRange of the "minecraft" dependency.
+var modsTomlField = regexp.MustCompile(`(?m)^\s*(modId|version|displayName)\s*=\s*"([^"]*)"`)
+var modsTomlMCVersion = regexp.MustCompile(`(?s)modId\s*=\s*"minecraft".*?versionRange\s*=\s*"\[?([0-9.]+)`)
+
+func parseModsToml(f *zip.File) (*ModMetadata, error) {
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+
+	meta := &ModMetadata{Loader: "forge"}
+	if strings.Contains(text, "neoforge") {
+		meta.Loader = "neoforge"
+	}
+	for _, m := range modsTomlField.FindAllStringSubmatch(text, -1) {
+		switch {
+		case m[1] == "modId" && meta.ModID == "":
+			meta.ModID = m[2]
+		case m[1] == "version" && meta.Version == "":
+			meta.Version = m[2]
+		case m[1] == "displayName" && meta.Name == "":
+			meta.Name = m[2]
+		}
+	}
+	if m := modsTomlMCVersion.FindStringSubmatch(text); m != nil {
+		meta.MCVersion = m[1]
+	}
+	if meta.ModID == "" {
+		return nil, fmt.Errorf("mods.toml has no modId")
+	}
+	return meta, nil
+}
+
+func parseMcmodInfo(f *zip.File) (*ModMetadata, error) {
+	data, err := readZipFile(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []struct {
+		ModID      string   `json:"modid"`
+		Name       string   `json:"name"`
+		Version    string   `json:"version"`
+		MCVersion  string   `json:"mcversion"`
+		AuthorList []string `json:"authorList"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing mcmod.info: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("mcmod.info has no entries")
+	}
+
+	e := entries[0]
+	return &ModMetadata{
+		ModID:     e.ModID,
+		Name:      e.Name,
+		Version:   e.Version,
+		MCVersion: e.MCVersion,
+		Loader:    "forge",
+		Authors:   e.AuthorList,
+	}, nil
+}
+
+func stringifyAuthors(raw []any) []string {
+	authors := make([]string, 0, len(raw))
+	for _, a := range raw {
+		switch v := a.(type) {
+		case string:
+			authors = append(authors, v)
+		case map[string]any:
+			if name, ok := v["name"].(string); ok {
+				authors = append(authors, name)
+			}
+		}
+	}
+	return authors
+}
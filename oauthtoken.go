@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// tokenCache centralizes the file-based OAuth token persistence every
+// OAuth backend needs, so Drive and OneDrive reuse the same on-disk pattern
+// instead of each backend growing its own token.json clone. Dropbox does not
+// use it: it reads a long-lived token from DROPBOX_ACCESS_TOKEN.
+type tokenCache struct {
+	provider string
+}
+
+func newTokenCache(provider string) *tokenCache {
+	return &tokenCache{provider: provider}
+}
+
+// path returns the provider's token file. "google" keeps the historical
+// "token.json" name so existing deployments don't lose their cached token;
+// every other provider gets "token_<provider>.json" unless overridden.
+func (t *tokenCache) path() string {
+	envKey := "TOKEN_PATH_" + t.provider
+	if v := os.Getenv(envKey); v != "" {
+		return v
+	}
+	if t.provider == "google" {
+		return "token.json"
+	}
+	return "token_" + t.provider + ".json"
+}
+
+// exists reports whether a token is already cached for this provider,
+// without attempting to decode it. getClient does not call it: it refuses a
+// live backend switch when load fails.
+func (t *tokenCache) exists() bool {
+	_, err := os.Stat(t.path())
+	return err == nil
+}
+
+func (t *tokenCache) load(out any) error {
+	f, err := os.Open(t.path())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewDecoder(f).Decode(out)
+}
+
+func (t *tokenCache) save(token any) error {
+	f, err := os.OpenFile(t.path(), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(token)
+}
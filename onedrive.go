@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// oneDriveStorage is the Storage backend for Microsoft OneDrive/SharePoint,
+// talking to the Microsoft Graph API with an OAuth2 token obtained through
+// the authorization-code flow and cached the same way the Drive backend
+// caches its token.
+type oneDriveStorage struct {
+	client     *http.Client
+	folderPath string // e.g. "MinecraftMods", relative to the drive root
+}
+
+func newOneDriveStorage(allowInteractive bool) (*oneDriveStorage, error) {
+	clientID := os.Getenv("ONEDRIVE_CLIENT_ID")
+	clientSecret := os.Getenv("ONEDRIVE_CLIENT_SECRET")
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("ONEDRIVE_CLIENT_ID and ONEDRIVE_CLIENT_SECRET must be set")
+	}
+
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  "http://localhost",
+		Scopes:       []string{"Files.ReadWrite", "offline_access"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
+			TokenURL: "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+		},
+	}
+
+	client, err := getClient("onedrive", config, allowInteractive)
+	if err != nil {
+		return nil, err
+	}
+	folder := envOrDefault("ONEDRIVE_FOLDER", "MinecraftMods")
+	return &oneDriveStorage{client: client, folderPath: folder}, nil
+}
+
+func (s *oneDriveStorage) Type() string { return "onedrive" }
+
+func (s *oneDriveStorage) itemURL(suffix string) string {
+	return fmt.Sprintf("https://graph.microsoft.com/v1.0/me/drive/root:/%s%s", s.folderPath, suffix)
+}
+
+func (s *oneDriveStorage) Put(name string, r io.Reader, size int64) (FileRef, error) {
+	req, err := http.NewRequest(http.MethodPut, s.itemURL("/"+name+":/content"), r)
+	if err != nil {
+		return FileRef{}, err
+	}
+	req.ContentLength = size
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return FileRef{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return FileRef{}, fmt.Errorf("onedrive upload failed with status %d", resp.StatusCode)
+	}
+
+	var item struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Size int64  `json:"size"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		return FileRef{}, err
+	}
+	return FileRef{ID: item.ID, Name: item.Name, Size: item.Size, ModTime: time.Now()}, nil
+}
+
+func (s *oneDriveStorage) List(ctx context.Context) ([]FileRef, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.itemURL(":/children"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("onedrive list failed with status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Value []struct {
+			ID                   string `json:"id"`
+			Name                 string `json:"name"`
+			Size                 int64  `json:"size"`
+			LastModifiedDateTime string `json:"lastModifiedDateTime"`
+		} `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	refs := make([]FileRef, 0, len(payload.Value))
+	for _, v := range payload.Value {
+		modTime, _ := time.Parse(time.RFC3339, v.LastModifiedDateTime)
+		refs = append(refs, FileRef{ID: v.ID, Name: v.Name, Size: v.Size, ModTime: modTime})
+	}
+	return refs, nil
+}
+
+func (s *oneDriveStorage) Delete(id string) error {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("https://graph.microsoft.com/v1.0/me/drive/items/%s", id), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("onedrive delete failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *oneDriveStorage) Get(id string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://graph.microsoft.com/v1.0/me/drive/items/%s/content", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("onedrive download failed with status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
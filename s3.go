@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3Storage is the Storage backend for S3-compatible object storage
+// (AWS S3, MinIO, Cloudflare R2, ...), selected by pointing S3_ENDPOINT at
+// whichever provider is in use.
+type s3Storage struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Storage() (*s3Storage, error) {
+	bucket := os.Getenv("S3_BUCKET")
+	if bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET must be set")
+	}
+
+	ctx := context.Background()
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(envOrDefault("S3_REGION", "auto")),
+	}
+	if key, secret := os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("S3_SECRET_ACCESS_KEY"); key != "" && secret != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(key, secret, "")))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load S3 config: %v", err)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+		o.UsePathStyle = os.Getenv("S3_USE_PATH_STYLE") == "true"
+	})
+
+	return &s3Storage{client: client, bucket: bucket, prefix: envOrDefault("S3_PREFIX", "MinecraftMods/")}, nil
+}
+
+func (s *s3Storage) Type() string { return "s3" }
+
+func (s *s3Storage) key(name string) string { return s.prefix + name }
+
+func (s *s3Storage) Put(name string, r io.Reader, size int64) (FileRef, error) {
+	key := s.key(name)
+	_, err := s.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          r,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return FileRef{}, fmt.Errorf("s3 upload failed: %v", err)
+	}
+	return FileRef{ID: key, Name: name, Size: size}, nil
+}
+
+func (s *s3Storage) List(ctx context.Context) ([]FileRef, error) {
+	out, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 list failed: %v", err)
+	}
+
+	refs := make([]FileRef, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		key := aws.ToString(obj.Key)
+		refs = append(refs, FileRef{
+			ID:      key,
+			Name:    key[len(s.prefix):],
+			Size:    aws.ToInt64(obj.Size),
+			ModTime: aws.ToTime(obj.LastModified),
+		})
+	}
+	return refs, nil
+}
+
+func (s *s3Storage) Delete(id string) error {
+	_, err := s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(id),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 delete failed: %v", err)
+	}
+	return nil
+}
+
+func (s *s3Storage) Get(id string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 download failed: %v", err)
+	}
+	return out.Body, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+type sessionState int
+
+const (
+	stateAnonymous sessionState = iota
+	stateAwaitingPassword
+	stateAuthenticated
+)
+
+// chatSession is one chat's login state. Sessions are per-chat (not global,
+// unlike the old single isFirstTimeSetup flag) so one user's login never
+// affects another chat's.
+type chatSession struct {
+	state          sessionState
+	telegramUserID int64
+	role           Role
+	lastActivity   time.Time
+
+	failedAttempts int
+	lockedUntil    time.Time
+}
+
+// sessionManager tracks chatSessions in memory with an idle timeout and
+// exponential backoff against password brute-forcing.
+type sessionManager struct {
+	mu          sync.Mutex
+	sessions    map[int64]*chatSession
+	idleTimeout time.Duration
+	maxAttempts int
+}
+
+func newSessionManager(idleTimeout time.Duration, maxAttempts int) *sessionManager {
+	return &sessionManager{
+		sessions:    make(map[int64]*chatSession),
+		idleTimeout: idleTimeout,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (m *sessionManager) get(chatID int64) *chatSession {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[chatID]
+	if !ok {
+		s = &chatSession{state: stateAnonymous}
+		m.sessions[chatID] = s
+	}
+	if s.state == stateAuthenticated && time.Since(s.lastActivity) > m.idleTimeout {
+		s.state = stateAnonymous
+		s.role = ""
+	}
+	return s
+}
+
+func (m *sessionManager) beginLogin(chatID, telegramUserID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[chatID]
+	if !ok {
+		s = &chatSession{}
+		m.sessions[chatID] = s
+	}
+	s.state = stateAwaitingPassword
+	s.telegramUserID = telegramUserID
+}
+
+// backoffRemaining returns how long a locked-out chat still has to wait, or
+// zero if it may attempt a password now.
+func (m *sessionManager) backoffRemaining(chatID int64) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[chatID]
+	if !ok || s.lockedUntil.IsZero() {
+		return 0
+	}
+	if remaining := time.Until(s.lockedUntil); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// recordFailure applies exponential backoff after a wrong password, locking
+// the chat out for longer after each consecutive failure, and ends the
+// session once maxAttempts consecutive failures are reached.
+func (m *sessionManager) recordFailure(chatID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[chatID]
+	if !ok {
+		return
+	}
+	s.failedAttempts++
+	backoff := time.Duration(1<<uint(min(s.failedAttempts, 6))) * time.Second
+	s.lockedUntil = time.Now().Add(backoff)
+	if s.failedAttempts >= m.maxAttempts {
+		s.state = stateAnonymous
+	}
+}
+
+func (m *sessionManager) authenticate(chatID int64, role Role) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[chatID]
+	if !ok {
+		s = &chatSession{}
+		m.sessions[chatID] = s
+	}
+	s.state = stateAuthenticated
+	s.role = role
+	s.failedAttempts = 0
+	s.lastActivity = time.Now()
+}
+
+func (m *sessionManager) touch(chatID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[chatID]; ok {
+		s.lastActivity = time.Now()
+	}
+}
+
+// invalidateUser logs out every chat session belonging to a revoked user.
+func (m *sessionManager) invalidateUser(telegramUserID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.telegramUserID == telegramUserID {
+			s.state = stateAnonymous
+			s.role = ""
+		}
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// FileRef is the backend-agnostic description of a stored file, returned by
+// every Storage implementation so handlers never need to know which backend
+// produced it.
+type FileRef struct {
+	ID      string
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage is implemented by every upload backend (Drive, OneDrive, Dropbox,
+// S3-compatible, local filesystem). Handlers talk to this interface rather
+// than to a concrete backend so STORAGE_BACKEND can switch providers without
+// touching bot logic.
+type Storage interface {
+	Put(name string, r io.Reader, size int64) (FileRef, error)
+	List(ctx context.Context) ([]FileRef, error)
+	Delete(id string) error
+	Get(id string) (io.ReadCloser, error)
+	Type() string
+}
+
+// ResumableStorage is an optional extension for backends that support
+// chunked, resumable uploads with progress callbacks (currently only Drive).
+type ResumableStorage interface {
+	Storage
+	PutResumable(bot *tele.Bot, store *uploadStateStore, chatID int64, name string, r io.Reader, progressMsg *tele.Message) error
+}
+
+// NewStorage builds the Storage backend selected by STORAGE_BACKEND (default
+// "drive"). Each backend reads its own credential env block. allowInteractive
+// gates whether an OAuth backend (drive, onedrive) may fall back to the
+// stdin-blocking authorization prompt when no token is cached yet — only
+// true at startup; a live /backend switch must already have one cached.
+func NewStorage(ctx context.Context, backend string, allowInteractive bool) (Storage, error) {
+	switch backend {
+	case "", "drive":
+		dm, err := initGoogleDrive(allowInteractive)
+		if err != nil {
+			return nil, fmt.Errorf("unable to init drive backend: %v", err)
+		}
+		return dm, nil
+	case "local":
+		return newLocalStorage(envOrDefault("LOCAL_STORAGE_DIR", "./storage"))
+	case "onedrive":
+		return newOneDriveStorage(allowInteractive)
+	case "dropbox":
+		return newDropboxStorage()
+	case "s3":
+		return newS3Storage()
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+func currentBackendName() string {
+	return envOrDefault("STORAGE_BACKEND", "drive")
+}
+
+// availableBackends lists the backend names /backend can switch between.
+func availableBackends() []string {
+	return []string{"drive", "onedrive", "dropbox", "s3", "local"}
+}
+
+// localStorage is the plain filesystem backend, mainly useful for
+// development or self-hosted setups without any cloud credentials.
+type localStorage struct {
+	dir string
+}
+
+func newLocalStorage(dir string) (*localStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create local storage dir: %v", err)
+	}
+	return &localStorage{dir: dir}, nil
+}
+
+func (s *localStorage) Type() string { return "local" }
+
+// localPath confines a caller-supplied name/id to s.dir, the same way
+// sanitizeUploadFileName confines staged upload names: take only the base
+// component and reject anything that isn't a real file name.
+func (s *localStorage) localPath(name string) (string, error) {
+	base := filepath.Base(name)
+	if base == "" || base == "." || base == ".." {
+		return "", fmt.Errorf("invalid file name: %q", name)
+	}
+	return filepath.Join(s.dir, base), nil
+}
+
+func (s *localStorage) Put(name string, r io.Reader, size int64) (FileRef, error) {
+	path, err := s.localPath(name)
+	if err != nil {
+		return FileRef{}, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return FileRef{}, err
+	}
+	defer f.Close()
+
+	written, err := io.Copy(f, r)
+	if err != nil {
+		return FileRef{}, err
+	}
+	return FileRef{ID: name, Name: name, Size: written, ModTime: time.Now()}, nil
+}
+
+func (s *localStorage) List(ctx context.Context) ([]FileRef, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var refs []FileRef
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		refs = append(refs, FileRef{ID: e.Name(), Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return refs, nil
+}
+
+func (s *localStorage) Delete(id string) error {
+	path, err := s.localPath(id)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *localStorage) Get(id string) (io.ReadCloser, error) {
+	path, err := s.localPath(id)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
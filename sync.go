@@ -0,0 +1,325 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// syncJobDirection says which way a single sync job moves a file.
+type syncJobDirection int
+
+const (
+	syncUpload syncJobDirection = iota
+	syncDownload
+)
+
+// syncJob is one file that needs to move between the local mirror and Drive.
+type syncJob struct {
+	direction syncJobDirection
+	name      string
+	driveID   string // set for downloads
+}
+
+// syncState is the on-disk record of the last sync run. Each run still does
+// a full name+md5 diff of both sides (a two-way mirror has no local change
+// journal to consult, so there's no way to skip that without one); this just
+// records when it last happened for /sync status.
+type syncState struct {
+	LastSyncAt time.Time `json:"last_sync_at"`
+}
+
+type syncStateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newSyncStateStore(path string) *syncStateStore {
+	return &syncStateStore{path: path}
+}
+
+func (s *syncStateStore) load() (*syncState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &syncState{}, nil
+		}
+		return nil, err
+	}
+	st := &syncState{}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *syncStateStore) save(st *syncState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// syncWorker mirrors a local directory and a Drive folder in both
+// directions, à la rclone: missing-or-newer files move in whichever
+// direction makes the two sides agree.
+type syncWorker struct {
+	dm       *DriveManager
+	localDir string
+	interval time.Duration
+	state    *syncStateStore
+	workers  int
+
+	mu      sync.Mutex
+	paused  bool
+	syncing bool
+}
+
+func newSyncWorker(dm *DriveManager, localDir string, interval time.Duration) *syncWorker {
+	return &syncWorker{
+		dm:       dm,
+		localDir: localDir,
+		interval: interval,
+		state:    newSyncStateStore(envOrDefault("SYNC_STATE_PATH", "sync_state.json")),
+		workers:  3,
+	}
+}
+
+// Start runs the sync loop in the background until the process exits.
+func (w *syncWorker) Start() {
+	go func() {
+		w.runOnce()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			w.runOnce()
+		}
+	}()
+}
+
+func (w *syncWorker) isPaused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused
+}
+
+func (w *syncWorker) setPaused(paused bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = paused
+}
+
+func (w *syncWorker) status() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st, _ := w.state.load()
+	state := "idle"
+	if w.syncing {
+		state = "syncing"
+	} else if w.paused {
+		state = "paused"
+	}
+	return fmt.Sprintf("Sync status: %s\nLocal dir: %s\nLast sync: %s\n\n⚠️ Each run does a full scan of both sides (not yet incremental via Drive's Changes API).", state, w.localDir, st.LastSyncAt.Format(time.RFC3339))
+}
+
+// runOnce diffs the local mirror against Drive and transfers whatever is
+// missing or newer on either side, using a small worker pool so transfers
+// happen in parallel.
+func (w *syncWorker) runOnce() {
+	if w.isPaused() {
+		return
+	}
+	w.mu.Lock()
+	w.syncing = true
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		w.syncing = false
+		w.mu.Unlock()
+	}()
+
+	st, err := w.state.load()
+	if err != nil {
+		st = &syncState{}
+	}
+
+	jobs, err := w.diff()
+	if err != nil {
+		fmt.Printf("sync: diff failed: %v\n", err)
+		return
+	}
+
+	jobCh := make(chan syncJob)
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobCh {
+				if err := w.runJob(job); err != nil {
+					fmt.Printf("sync: job for %s failed: %v\n", job.name, err)
+				}
+			}
+		}()
+	}
+	for _, job := range jobs {
+		jobCh <- job
+	}
+	close(jobCh)
+	wg.Wait()
+
+	st.LastSyncAt = time.Now()
+	w.state.save(st)
+}
+
+// diff lists both sides and returns the jobs needed to reconcile them,
+// comparing by file name and md5 so unchanged files are skipped.
+func (w *syncWorker) diff() ([]syncJob, error) {
+	localFiles, err := w.listLocal()
+	if err != nil {
+		return nil, fmt.Errorf("listing local mirror: %v", err)
+	}
+	remoteFiles, err := w.listRemote()
+	if err != nil {
+		return nil, fmt.Errorf("listing remote folder: %v", err)
+	}
+
+	var jobs []syncJob
+	for name, local := range localFiles {
+		remote, ok := remoteFiles[name]
+		if !ok {
+			jobs = append(jobs, syncJob{direction: syncUpload, name: name})
+			continue
+		}
+		if remote.Md5Checksum == local.md5 {
+			continue
+		}
+		// Both sides differ: the copy with the later modification time wins.
+		info, err := os.Stat(local.path)
+		remoteMod, perr := time.Parse(time.RFC3339, remote.ModifiedTime)
+		if err != nil || perr != nil {
+			continue
+		}
+		if info.ModTime().After(remoteMod) {
+			jobs = append(jobs, syncJob{direction: syncUpload, name: name})
+		} else {
+			jobs = append(jobs, syncJob{direction: syncDownload, name: name, driveID: remote.Id})
+		}
+	}
+	for name, remote := range remoteFiles {
+		if _, ok := localFiles[name]; !ok {
+			jobs = append(jobs, syncJob{direction: syncDownload, name: name, driveID: remote.Id})
+		}
+	}
+	return jobs, nil
+}
+
+type localFileInfo struct {
+	path string
+	md5  string
+}
+
+func (w *syncWorker) listLocal() (map[string]localFileInfo, error) {
+	entries, err := os.ReadDir(w.localDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]localFileInfo{}, nil
+		}
+		return nil, err
+	}
+
+	out := make(map[string]localFileInfo, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		path := filepath.Join(w.localDir, e.Name())
+		sum, err := md5File(path)
+		if err != nil {
+			continue
+		}
+		out[e.Name()] = localFileInfo{path: path, md5: sum}
+	}
+	return out, nil
+}
+
+func (w *syncWorker) listRemote() (map[string]*drive.File, error) {
+	r, err := w.dm.driveListQuery(w.dm.readService.Files.List()).
+		Q(fmt.Sprintf("'%s' in parents and trashed=false", w.dm.folderID)).
+		Fields("files(id,name,md5Checksum,modifiedTime,size)").
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*drive.File, len(r.Files))
+	for _, f := range r.Files {
+		out[f.Name] = f
+	}
+	return out, nil
+}
+
+func (w *syncWorker) runJob(job syncJob) error {
+	switch job.direction {
+	case syncUpload:
+		f, err := os.Open(filepath.Join(w.localDir, job.name))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return w.dm.uploadFileTo(w.dm.folderID, job.name, f)
+	case syncDownload:
+		// Drive file names aren't restricted by the API and may contain "/" or
+		// "..", so the remote name can't be trusted verbatim as a local path
+		// component (same class of bug fixed in sanitizeUploadFileName/localPath).
+		safeName, err := sanitizeUploadFileName(job.name)
+		if err != nil {
+			return err
+		}
+		rc, err := w.dm.Get(job.driveID)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := os.MkdirAll(w.localDir, 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(filepath.Join(w.localDir, safeName))
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		_, err = io.Copy(out, rc)
+		return err
+	default:
+		return fmt.Errorf("unknown sync direction")
+	}
+}
+
+func md5File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
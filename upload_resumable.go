@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// sanitizeUploadFileName strips any directory components from an
+// attacker-controlled Telegram document name before it's used to build a
+// local staging path, so a traversal payload (e.g. "../../../../root/x.jar")
+// can't make the bot write outside os.TempDir().
+func sanitizeUploadFileName(name string) (string, error) {
+	base := filepath.Base(name)
+	if base == "" || base == "." || base == ".." {
+		return "", fmt.Errorf("invalid file name: %q", name)
+	}
+	return base, nil
+}
+
+// resumableUploadState is the on-disk record for one chunked upload, either still
+// in progress or interrupted by a dropped connection or bot restart.
+type resumableUploadState struct {
+	ChatID    int64     `json:"chat_id"`
+	FileName  string    `json:"file_name"`
+	SHA256    string    `json:"sha256"`
+	TotalSize int64     `json:"total_size"`
+	Offset    int64     `json:"offset"`
+	Location  string    `json:"location"`
+	TempPath  string    `json:"temp_path"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func resumeKey(chatID int64, fileName, sha256Hex string) string {
+	return fmt.Sprintf("%d|%s|%s", chatID, fileName, sha256Hex)
+}
+
+// uploadStateStore persists resumableUploadState records to a JSON file so an
+// upload interrupted mid-chunk can pick up where it left off instead of
+// restarting from byte zero.
+type uploadStateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newUploadStateStore(path string) *uploadStateStore {
+	return &uploadStateStore{path: path}
+}
+
+func (s *uploadStateStore) load() (map[string]*resumableUploadState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make(map[string]*resumableUploadState)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return states, nil
+		}
+		return nil, err
+	}
+	if len(data) == 0 {
+		return states, nil
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+func (s *uploadStateStore) save(states map[string]*resumableUploadState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func (s *uploadStateStore) put(st *resumableUploadState) error {
+	states, err := s.load()
+	if err != nil {
+		return err
+	}
+	states[resumeKey(st.ChatID, st.FileName, st.SHA256)] = st
+	return s.save(states)
+}
+
+func (s *uploadStateStore) delete(key string) error {
+	states, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(states, key)
+	return s.save(states)
+}
+
+func (s *uploadStateStore) forChat(chatID int64) ([]*resumableUploadState, error) {
+	states, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var out []*resumableUploadState
+	for _, st := range states {
+		if st.ChatID == chatID {
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+
+// chunkSizeBytes returns the configured upload chunk size, rounded down to the
+// 256 KiB alignment Drive's resumable protocol requires.
+func chunkSizeBytes() int64 {
+	const defaultMiB = 8
+	const alignment = 256 * 1024
+
+	mib := defaultMiB
+	if v := os.Getenv("UPLOAD_CHUNK_SIZE"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			mib = parsed
+		}
+	}
+	size := int64(mib) * 1024 * 1024
+	return size - (size % alignment)
+}
+
+func uploadMaxRetries() int {
+	if v := os.Getenv("UPLOAD_MAX_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 5
+}
+
+// initResumableSession performs the POST handshake that starts a Drive v3
+// resumable upload session and returns the session's Location URL.
+func (dm *DriveManager) initResumableSession(fileName string, size int64, contentType, folderID string) (string, error) {
+	meta := map[string]any{"name": fileName}
+	if folderID != "" {
+		meta["parents"] = []string{folderID}
+	}
+	body, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		"https://www.googleapis.com/upload/drive/v3/files?uploadType=resumable&supportsAllDrives=true",
+		bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Upload-Content-Type", contentType)
+	req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+
+	resp, err := dm.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("resumable session init failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("resumable session init returned status %d", resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return "", fmt.Errorf("drive did not return a resumable session Location")
+	}
+	return location, nil
+}
+
+// queryResumableOffset asks Drive how many bytes of a resumable session it has
+// already committed, per the `Content-Range: bytes */<total>` probe.
+func queryResumableOffset(client *http.Client, location string, total int64) (int64, error) {
+	req, err := http.NewRequest(http.MethodPut, location, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", total))
+	req.Header.Set("Content-Length", "0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return total, nil
+	case 308:
+		rng := resp.Header.Get("Range")
+		if rng == "" {
+			return 0, nil
+		}
+		var start, end int64
+		if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+			return 0, nil
+		}
+		return end + 1, nil
+	default:
+		return 0, fmt.Errorf("unexpected status %d probing resumable offset", resp.StatusCode)
+	}
+}
+
+// uploadChunks streams f to the resumable session starting at offset, invoking
+// onProgress after every committed chunk.
+func (dm *DriveManager) uploadChunks(location string, f *os.File, total, offset, chunkSize int64, onProgress func(sent, total int64)) error {
+	buf := make([]byte, chunkSize)
+	for offset < total {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		end := offset + int64(n) - 1
+
+		req, err := http.NewRequest(http.MethodPut, location, bytes.NewReader(buf[:n]))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, end, total))
+		req.Header.Set("Content-Length", strconv.Itoa(n))
+
+		resp, err := dm.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		status := resp.StatusCode
+		resp.Body.Close()
+
+		switch status {
+		case 308, http.StatusOK, http.StatusCreated:
+			offset = end + 1
+			onProgress(offset, total)
+		default:
+			return fmt.Errorf("chunk upload failed with status %d", status)
+		}
+	}
+	return nil
+}
+
+// progressReporter throttles Telegram message edits so a fast upload doesn't
+// hit the bot API's rate limits.
+type progressReporter struct {
+	bot       *tele.Bot
+	msg       *tele.Message
+	fileName  string
+	lastEdit  time.Time
+	startedAt time.Time
+}
+
+func newProgressReporter(bot *tele.Bot, msg *tele.Message, fileName string) *progressReporter {
+	return &progressReporter{bot: bot, msg: msg, fileName: fileName, startedAt: time.Now()}
+}
+
+func (p *progressReporter) report(sent, total int64) {
+	now := time.Now()
+	if sent < total && now.Sub(p.lastEdit) < 2*time.Second {
+		return
+	}
+	p.lastEdit = now
+
+	elapsed := now.Sub(p.startedAt).Seconds()
+	percent := float64(0)
+	if total > 0 {
+		percent = float64(sent) / float64(total) * 100
+	}
+	mbps := float64(0)
+	if elapsed > 0 {
+		mbps = float64(sent) / 1024 / 1024 / elapsed
+	}
+	var eta time.Duration
+	if sent > 0 && sent < total {
+		remaining := total - sent
+		eta = time.Duration(float64(remaining)/float64(sent)*elapsed) * time.Second
+	}
+
+	text := fmt.Sprintf("⏳ Uploading %s...\n%.1f%% • %.2f MB/s", p.fileName, percent, mbps)
+	if sent < total {
+		text += fmt.Sprintf(" • ETA %s", eta.Round(time.Second))
+	}
+
+	p.bot.Edit(p.msg, text)
+}
+
+// uploadFileResumable downloads a document to a local staging file, then
+// uploads it to Drive via the resumable protocol in UPLOAD_CHUNK_SIZE chunks,
+// persisting progress so a dropped connection or bot restart can resume
+// instead of starting over.
+func (dm *DriveManager) uploadFileResumable(bot *tele.Bot, store *uploadStateStore, chatID int64, fileName string, src io.Reader, progressMsg *tele.Message) error {
+	return dm.uploadFileResumableTo(bot, store, chatID, dm.folderID, fileName, src, progressMsg)
+}
+
+// uploadFileResumableTo is uploadFileResumable scoped to a specific folder,
+// which is how per-chat folder routing places chunked uploads.
+func (dm *DriveManager) uploadFileResumableTo(bot *tele.Bot, store *uploadStateStore, chatID int64, folderID, fileName string, src io.Reader, progressMsg *tele.Message) error {
+	safeName, err := sanitizeUploadFileName(fileName)
+	if err != nil {
+		return err
+	}
+	tempPath := filepath.Join(os.TempDir(), fmt.Sprintf("modupload-%d-%s", chatID, safeName))
+	tempFile, err := os.Create(tempPath)
+	if err != nil {
+		return fmt.Errorf("unable to stage upload: %v", err)
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(tempFile, io.TeeReader(src, hasher))
+	tempFile.Close()
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("unable to download file: %v", err)
+	}
+	defer os.Remove(tempPath)
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	key := resumeKey(chatID, fileName, sum)
+
+	states, err := store.load()
+	if err != nil {
+		return fmt.Errorf("unable to read upload state: %v", err)
+	}
+
+	state, resuming := states[key]
+	if !resuming {
+		location, err := dm.initResumableSession(fileName, size, "application/java-archive", folderID)
+		if err != nil {
+			return err
+		}
+		state = &resumableUploadState{
+			ChatID: chatID, FileName: fileName, SHA256: sum,
+			TotalSize: size, Location: location, TempPath: tempPath, StartedAt: time.Now(),
+		}
+	} else {
+		offset, err := queryResumableOffset(dm.httpClient, state.Location, state.TotalSize)
+		if err != nil {
+			return fmt.Errorf("unable to resume upload: %v", err)
+		}
+		state.Offset = offset
+	}
+	if err := store.put(state); err != nil {
+		return err
+	}
+
+	f, err := os.Open(tempPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reporter := newProgressReporter(bot, progressMsg, fileName)
+	chunkSize := chunkSizeBytes()
+	maxRetries := uploadMaxRetries()
+
+	var uploadErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		uploadErr = dm.uploadChunks(state.Location, f, state.TotalSize, state.Offset, chunkSize, func(sent, total int64) {
+			state.Offset = sent
+			store.put(state)
+			reporter.report(sent, total)
+		})
+		if uploadErr == nil {
+			break
+		}
+		offset, probeErr := queryResumableOffset(dm.httpClient, state.Location, state.TotalSize)
+		if probeErr != nil {
+			continue
+		}
+		state.Offset = offset
+	}
+	if uploadErr != nil {
+		return fmt.Errorf("upload failed after %d retries: %v", maxRetries, uploadErr)
+	}
+
+	store.delete(key)
+	return nil
+}